@@ -0,0 +1,292 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tmux "github.com/brotholo/go-tmux"
+)
+
+// Options controls which parts of a Config are acted on and how the
+// resulting session is presented to the user.
+type Options struct {
+	// Windows restricts Start to the named windows, e.g. the
+	// "project:window1,window2" / "-w window1,window2" CLI shape. A nil
+	// or empty slice means all windows. Stop ignores it and always kills
+	// the whole session.
+	Windows []string
+
+	// Attach controls whether Start attaches/switches to the session
+	// after creating it.
+	Attach bool
+}
+
+// wantsWindow reports whether w should be processed given opts.
+func (o Options) wantsWindow(w WindowConfig) bool {
+	if len(o.Windows) == 0 {
+		return true
+	}
+	for _, name := range o.Windows {
+		if name == w.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Start creates (or attaches to) the session described by cfg.
+//
+// If a session named cfg.Name already exists, Start only attaches/switches
+// to it. Otherwise it runs BeforeStart, creates the session and its
+// windows/panes, and runs each pane's commands.
+func Start(cfg *Config, opts Options) error {
+	if cfg.Name == "" {
+		return fmt.Errorf("builder: config has no name")
+	}
+
+	if sessionExists(cfg.Name) {
+		if opts.Attach {
+			return attachOrSwitch(cfg.Name)
+		}
+		return nil
+	}
+
+	for _, cmd := range cfg.BeforeStart {
+		if err := runShell(cmd, cfg.Root); err != nil {
+			return fmt.Errorf("before_start %q: %w", cmd, err)
+		}
+	}
+
+	windows := selectWindows(cfg, opts)
+	if len(windows) == 0 {
+		return fmt.Errorf("builder: no windows to start")
+	}
+
+	for i, w := range windows {
+		root := w.Root
+		if root == "" {
+			root = cfg.Root
+		}
+
+		// newSession/newWindow create the window's first pane, so that
+		// pane's own Root (if set) must be applied there rather than
+		// being dropped on the floor.
+		firstPaneRoot := root
+		if len(w.Panes) > 0 && w.Panes[0].Root != "" {
+			firstPaneRoot = w.Panes[0].Root
+		}
+
+		if i == 0 {
+			if err := newSession(cfg.Name, w.Name, firstPaneRoot); err != nil {
+				return fmt.Errorf("create session %q: %w", cfg.Name, err)
+			}
+		} else {
+			if err := newWindow(cfg.Name, w.Name, firstPaneRoot); err != nil {
+				return fmt.Errorf("create window %q: %w", w.Name, err)
+			}
+		}
+
+		if err := buildWindow(cfg.Name, w, root); err != nil {
+			return err
+		}
+	}
+
+	if opts.Attach {
+		return attachOrSwitch(cfg.Name)
+	}
+	return nil
+}
+
+// Stop runs cfg.Stop's shell hooks and kills the session.
+func Stop(cfg *Config, opts Options) error {
+	if cfg.Name == "" {
+		return fmt.Errorf("builder: config has no name")
+	}
+
+	for _, cmd := range cfg.Stop {
+		if err := runShell(cmd, cfg.Root); err != nil {
+			return fmt.Errorf("stop %q: %w", cmd, err)
+		}
+	}
+
+	if !sessionExists(cfg.Name) {
+		return nil
+	}
+
+	_, stdErr, err := tmux.RunCmd([]string{"kill-session", "-t", cfg.Name})
+	if err != nil {
+		return fmt.Errorf("kill-session %q: %s: %w", cfg.Name, stdErr, err)
+	}
+	return nil
+}
+
+func selectWindows(cfg *Config, opts Options) []WindowConfig {
+	windows := make([]WindowConfig, 0, len(cfg.Windows))
+	for _, w := range cfg.Windows {
+		if opts.wantsWindow(w) {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// buildWindow applies layout, splits the configured panes and runs each
+// pane's commands plus the window's own commands.
+func buildWindow(session string, w WindowConfig, root string) error {
+	target := fmt.Sprintf("%s:%s", session, w.Name)
+
+	panes, err := splitPanes(target, w.Panes, root)
+	if err != nil {
+		return fmt.Errorf("split window %q: %w", w.Name, err)
+	}
+
+	if !w.Manual && w.Layout != "" {
+		if _, stdErr, err := tmux.RunCmd([]string{"select-layout", "-t", target, w.Layout}); err != nil {
+			return fmt.Errorf("select-layout %q: %s: %w", w.Layout, stdErr, err)
+		}
+	}
+
+	if len(panes) == 0 {
+		panes = []string{target}
+	}
+	for _, cmd := range w.Commands {
+		if err := sendKeys(panes[0], cmd); err != nil {
+			return err
+		}
+	}
+
+	for i, p := range w.Panes {
+		if i >= len(panes) {
+			break
+		}
+		for _, cmd := range p.Commands {
+			if err := sendKeys(panes[i], cmd); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// splitPanes creates one pane per PaneConfig (the first pane of a window
+// already exists from newSession/newWindow, so len(panes)-1 splits are
+// issued) and returns the target string of every resulting pane in order.
+func splitPanes(windowTarget string, panes []PaneConfig, defaultRoot string) ([]string, error) {
+	if len(panes) == 0 {
+		return []string{windowTarget}, nil
+	}
+
+	// Resolve the first pane's id before splitting: once split-window
+	// makes a new pane active, windowTarget would resolve to that one.
+	first, stdErr, err := tmux.RunCmd([]string{"display-message", "-p", "-t", windowTarget, "#{pane_id}"})
+	if err != nil {
+		return nil, fmt.Errorf("display-message: %s: %w", stdErr, err)
+	}
+	last := strings.TrimSpace(first)
+	targets := []string{last}
+	for i, p := range panes {
+		if i == 0 {
+			// The window's first pane already exists (newSession/newWindow
+			// created it, already passing panes[0].Root as -c); nothing
+			// to split here.
+			continue
+		}
+
+		root := p.Root
+		if root == "" {
+			root = defaultRoot
+		}
+
+		flag := "-h"
+		if strings.EqualFold(p.Type, "vertical") {
+			flag = "-v"
+		}
+
+		args := []string{"split-window", "-P", "-F", "#{pane_id}", flag, "-t", last}
+		if root != "" {
+			args = append(args, "-c", root)
+		}
+
+		out, stdErr, err := tmux.RunCmd(args)
+		if err != nil {
+			return nil, fmt.Errorf("split-window: %s: %w", stdErr, err)
+		}
+		last = strings.TrimSpace(out)
+		targets = append(targets, last)
+	}
+
+	return targets, nil
+}
+
+func sendKeys(target, cmd string) error {
+	_, stdErr, err := tmux.RunCmd([]string{"send-keys", "-t", target, cmd, "C-m"})
+	if err != nil {
+		return fmt.Errorf("send-keys %q: %s: %w", cmd, stdErr, err)
+	}
+	return nil
+}
+
+func newSession(session, windowName, root string) error {
+	args := []string{"new-session", "-d", "-s", session}
+	if windowName != "" {
+		args = append(args, "-n", windowName)
+	}
+	if root != "" {
+		args = append(args, "-c", root)
+	}
+	_, stdErr, err := tmux.RunCmd(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", stdErr, err)
+	}
+	return nil
+}
+
+func newWindow(session, windowName, root string) error {
+	args := []string{"new-window", "-t", session}
+	if windowName != "" {
+		args = append(args, "-n", windowName)
+	}
+	if root != "" {
+		args = append(args, "-c", root)
+	}
+	_, stdErr, err := tmux.RunCmd(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", stdErr, err)
+	}
+	return nil
+}
+
+func sessionExists(session string) bool {
+	_, _, err := tmux.RunCmd([]string{"has-session", "-t", session})
+	return err == nil
+}
+
+// attachOrSwitch attaches to the session, or switches the client to it if
+// we're already inside a tmux client (detected via $TMUX), mirroring how
+// tmuxinator/smug avoid nesting tmux sessions.
+func attachOrSwitch(session string) error {
+	if os.Getenv("TMUX") != "" {
+		_, stdErr, err := tmux.RunCmd([]string{"switch-client", "-t", session})
+		if err != nil {
+			return fmt.Errorf("switch-client: %s: %w", stdErr, err)
+		}
+		return nil
+	}
+
+	cmd := exec.Command("tmux", "attach", "-t", session)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func runShell(command, dir string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,113 @@
+// The MIT License (MIT)
+// Copyright (C) 2019 Georgy Komarov <jubnzv@gmail.com>
+
+// Package builder loads a declarative project file (YAML or TOML)
+// describing a tmux session and materializes it on top of package tmux.
+//
+// The file format mirrors what tmuxinator/tmuxctl/smug users expect:
+//
+//	name: myproject
+//	root: ~/code/myproject
+//	before_start:
+//	  - docker-compose up -d
+//	stop:
+//	  - docker-compose down
+//	windows:
+//	  - name: editor
+//	    root: ~/code/myproject
+//	    layout: main-vertical
+//	    panes:
+//	      - type: vertical
+//	        commands:
+//	          - vim .
+//	      - type: horizontal
+//	        commands:
+//	          - npm run dev
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// PaneConfig describes a single pane to create inside a window.
+type PaneConfig struct {
+	Type     string   `yaml:"type" toml:"type"`
+	Root     string   `yaml:"root" toml:"root"`
+	Commands []string `yaml:"commands" toml:"commands"`
+}
+
+// WindowConfig describes a single tmux window and its panes.
+type WindowConfig struct {
+	Name     string       `yaml:"name" toml:"name"`
+	Root     string       `yaml:"root" toml:"root"`
+	Layout   string       `yaml:"layout" toml:"layout"`
+	Manual   bool         `yaml:"manual" toml:"manual"`
+	Commands []string     `yaml:"commands" toml:"commands"`
+	Panes    []PaneConfig `yaml:"panes" toml:"panes"`
+}
+
+// Config is the top-level shape of a project file.
+type Config struct {
+	Name        string         `yaml:"name" toml:"name"`
+	Root        string         `yaml:"root" toml:"root"`
+	BeforeStart []string       `yaml:"before_start" toml:"before_start"`
+	Stop        []string       `yaml:"stop" toml:"stop"`
+	Windows     []WindowConfig `yaml:"windows" toml:"windows"`
+}
+
+// Load reads a project file and parses it as YAML or TOML, chosen by
+// the file extension (.yml/.yaml or .toml).
+func Load(path string) (*Config, error) {
+	path = ExpandPath(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	cfg := &Config{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yml", ".yaml":
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse yaml config %s: %w", path, err)
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(data), cfg); err != nil {
+			return nil, fmt.Errorf("parse toml config %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config extension %q", ext)
+	}
+
+	cfg.Root = ExpandPath(cfg.Root)
+	for i := range cfg.Windows {
+		cfg.Windows[i].Root = ExpandPath(cfg.Windows[i].Root)
+		for j := range cfg.Windows[i].Panes {
+			cfg.Windows[i].Panes[j].Root = ExpandPath(cfg.Windows[i].Panes[j].Root)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ExpandPath expands a leading "~/", or a bare "~", into the current user's
+// home directory. Any other path is returned unchanged.
+func ExpandPath(path string) string {
+	if path == "" || path[0] != '~' {
+		return path
+	}
+	if len(path) == 1 || path[1] == '/' {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return path
+		}
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
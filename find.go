@@ -0,0 +1,52 @@
+// The MIT License (MIT)
+// Copyright (C) 2019 Georgy Komarov <jubnzv@gmail.com>
+
+package tmux
+
+import "path/filepath"
+
+// FindPaneInDir looks for an existing pane across all sessions whose
+// current working directory matches dir, so callers can reuse a pane
+// that's already there instead of always splitting a new one. The second
+// return value reports whether a match was found.
+func FindPaneInDir(dir string) (*Pane, bool, error) {
+	want, err := cleanDir(dir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	panes, err := ListPanes([]string{"-a"})
+	if err != nil {
+		return nil, false, err
+	}
+
+	for i := range panes {
+		path, err := cleanDir(panes[i].CurrentPath)
+		if err != nil {
+			continue
+		}
+		if path == want {
+			return &panes[i], true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// cleanDir resolves dir to an absolute, symlink-free path so that
+// directories reached via different paths (e.g. a symlinked project
+// checkout) still compare equal.
+func cleanDir(dir string) (string, error) {
+	dir = filepath.Clean(dir)
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return filepath.Clean(abs), nil
+	}
+	return resolved, nil
+}
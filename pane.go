@@ -4,15 +4,116 @@
 package tmux
 
 import (
+	"crypto/sha256"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
-const (
-	paneParts = 7
-)
+// paneSep separates fields in the format strings passed to list-panes and
+// split-window. It's the ASCII Unit Separator, which cannot appear in a
+// session/window/pane name, unlike ":" which regularly does.
+const paneSep = "\x1f"
+
+const paneFields = 14
+
+// paneFormat is the list-panes/split-window format string shared by
+// ListPanes and Split so both parse exactly the same fields, in the same
+// order ParsePaneLine expects.
+var paneFormat = strings.Join([]string{
+	"#{session_id}",
+	"#{session_name}",
+	"#{window_id}",
+	"#{window_name}",
+	"#{window_index}",
+	"#{pane_id}",
+	"#{pane_active}",
+	"#{pane_pid}",
+	"#{pane_current_command}",
+	"#{pane_current_path}",
+	"#{pane_width}",
+	"#{pane_height}",
+	"#{pane_title}",
+	"#{pane_start_command}",
+}, paneSep)
+
+// ParsePaneLine parses a single line produced with the paneFormat format
+// string (fields joined with sep) into a Pane.
+func ParsePaneLine(line, sep string) (Pane, error) {
+	fields := strings.Split(line, sep)
+	if len(fields) != paneFields {
+		return Pane{}, fmt.Errorf("tmux: expected %d fields, got %d: %q", paneFields, len(fields), line)
+	}
+
+	sessionID, err := strconv.Atoi(strings.TrimPrefix(fields[0], "$"))
+	if err != nil {
+		return Pane{}, fmt.Errorf("tmux: parse session_id %q: %w", fields[0], err)
+	}
+
+	windowID, err := strconv.Atoi(strings.TrimPrefix(fields[2], "@"))
+	if err != nil {
+		return Pane{}, fmt.Errorf("tmux: parse window_id %q: %w", fields[2], err)
+	}
+
+	windowIndex, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return Pane{}, fmt.Errorf("tmux: parse window_index %q: %w", fields[4], err)
+	}
+
+	paneIndex, err := strconv.Atoi(strings.TrimPrefix(fields[5], "%"))
+	if err != nil {
+		return Pane{}, fmt.Errorf("tmux: parse pane_id %q: %w", fields[5], err)
+	}
+
+	pid, err := strconv.Atoi(fields[7])
+	if err != nil {
+		return Pane{}, fmt.Errorf("tmux: parse pane_pid %q: %w", fields[7], err)
+	}
+
+	width, err := strconv.Atoi(fields[10])
+	if err != nil {
+		return Pane{}, fmt.Errorf("tmux: parse pane_width %q: %w", fields[10], err)
+	}
+
+	height, err := strconv.Atoi(fields[11])
+	if err != nil {
+		return Pane{}, fmt.Errorf("tmux: parse pane_height %q: %w", fields[11], err)
+	}
+
+	return Pane{
+		SessionId:      sessionID,
+		SessionName:    fields[1],
+		WindowId:       windowID,
+		WindowName:     fields[3],
+		WindowIndex:    windowIndex,
+		ID:             paneIndex,
+		Active:         fields[6] == "1",
+		Pid:            pid,
+		CurrentCommand: fields[8],
+		CurrentPath:    fields[9],
+		Width:          width,
+		Height:         height,
+		Title:          fields[12],
+		StartCommand:   fields[13],
+		target:         fields[5],
+		windowTarget:   fields[2],
+	}, nil
+}
+
+// parsePaneLine is a convenience wrapper around ParsePaneLine that parses
+// paneFormat-shaped output, the shape ListPanes and Split need. Empty lines
+// are skipped (ok is false); an unparsable line is returned as an error.
+func parsePaneLine(line string) (Pane, bool, error) {
+	if strings.TrimSpace(line) == "" {
+		return Pane{}, false, nil
+	}
+	pane, err := ParsePaneLine(line, paneSep)
+	if err != nil {
+		return Pane{}, false, err
+	}
+	return pane, true, nil
+}
 
 type Pane struct {
 	ID          int
@@ -22,6 +123,47 @@ type Pane struct {
 	WindowName  string
 	WindowIndex int
 	Active      bool
+
+	// Pid is the pane's pane_pid.
+	Pid int
+	// CurrentCommand is pane_current_command.
+	CurrentCommand string
+	// CurrentPath is pane_current_path.
+	CurrentPath string
+	// Width is pane_width.
+	Width int
+	// Height is pane_height.
+	Height int
+	// Title is pane_title.
+	Title string
+	// StartCommand is pane_start_command.
+	StartCommand string
+
+	// target caches the pane's "%<pane_id>" target, populated by
+	// ListPanes/Split. See Target.
+	target string
+	// windowTarget caches the pane's window's "@<window_id>" target.
+	windowTarget string
+}
+
+// Target returns the stable target for this pane, preferring the
+// "%<pane_id>" form over "session:window.pane" since the latter breaks
+// as soon as a session/window is renamed mid-operation. Falls back to
+// computing it from ID for Panes built by hand rather than ListPanes/Split.
+func (p *Pane) Target() string {
+	if p.target != "" {
+		return p.target
+	}
+	return fmt.Sprintf("%%%d", p.ID)
+}
+
+// windowTargetOf returns the stable "@<window_id>" target for the window
+// this pane belongs to.
+func (p *Pane) windowTargetOf() string {
+	if p.windowTarget != "" {
+		return p.windowTarget
+	}
+	return fmt.Sprintf("@%d", p.WindowId)
 }
 
 // Return list of panes. Optional arguments are define the search scope with
@@ -32,17 +174,7 @@ type Pane struct {
 //   - `-s`: target is a session. If neither is given, target is a window (or
 //     the current window).
 func ListPanes(args []string) ([]Pane, error) {
-	format := strings.Join([]string{
-		"#{session_id}",
-		"#{session_name}",
-		"#{window_id}",
-		"#{window_name}",
-		"#{window_index}",
-		"#{pane_id}",
-		"#{pane_active}",
-	}, ":")
-
-	args = append([]string{"list-panes", "-F", format}, args...)
+	args = append([]string{"list-panes", "-F", paneFormat}, args...)
 
 	out, _, err := RunCmd(args)
 	if err != nil {
@@ -51,60 +183,40 @@ func ListPanes(args []string) ([]Pane, error) {
 
 	outLines := strings.Split(out, "\n")
 	panes := []Pane{}
-	re := regexp.MustCompile(`\$([0-9]+):(.+):@([0-9]+):(.+):([0-9]+):%([0-9]+):([01])`)
 
 	for _, line := range outLines {
-		result := re.FindStringSubmatch(line)
-		if len(result) <= paneParts {
-			continue
+		pane, ok, err := parsePaneLine(line)
+		if err != nil {
+			return nil, err
 		}
-
-		sessionID, errAtoi := strconv.Atoi(result[1])
-		if errAtoi != nil {
-			return nil, errAtoi
-		}
-
-		windowID, errAtoi := strconv.Atoi(result[3])
-		if errAtoi != nil {
-			return nil, errAtoi
-		}
-
-		windowIndex, errAtoi := strconv.Atoi(result[5])
-		if errAtoi != nil {
-			return nil, errAtoi
-		}
-
-		paneIndex, errAtoi := strconv.Atoi(result[6])
-		if errAtoi != nil {
-			return nil, errAtoi
+		if !ok {
+			continue
 		}
-
-		panes = append(panes, Pane{
-			SessionId:   sessionID,
-			SessionName: result[2],
-			WindowId:    windowID,
-			WindowName:  result[4],
-			WindowIndex: windowIndex,
-			ID:          paneIndex,
-			Active:      result[7] == "1",
-		})
+		panes = append(panes, pane)
 	}
 
 	return panes, nil
 }
 
-// Returns current path for this pane.
+// Returns current path for this pane. If p was populated by ListPanes or
+// Split, CurrentPath is already known and is returned directly instead of
+// another round-trip to tmux.
 func (p *Pane) GetCurrentPath() (string, error) {
+	if p.CurrentPath != "" {
+		return p.CurrentPath, nil
+	}
+
 	args := []string{
 		"display-message",
-		"-P", "-F", "#{pane_current_path}"}
+		"-p", "-t", p.Target(),
+		"-F", "#{pane_current_path}"}
 	out, _, err := RunCmd(args)
 	if err != nil {
 		return "", err
 	}
 
-	// Remove trailing CR
-	out = out[:len(out)-1]
+	out = strings.TrimRight(out, "\r\n")
+	p.CurrentPath = out
 
 	return out, nil
 }
@@ -112,17 +224,12 @@ func (p *Pane) SetFocus() error {
 	args := []string{
 		"select-window",
 		"-t",
-		fmt.Sprintf("%s:%d", p.SessionName, p.WindowId)}
-	//  fmt.Sprintf("%s", p.WindowName)}
-	//  p.WindowName}
+		p.windowTargetOf()}
 	_, _, err := RunCmd(args)
-	//  fmt.Println("SET FOCUS DIO", p.WindowName,
 	args = []string{
 		"select-pane",
 		"-t",
-		fmt.Sprintf("%s:%d.%d", p.SessionName, p.WindowId, p.ID)}
-	//  fmt.Sprintf("%s", p.WindowName)}
-	//  p.WindowName}
+		p.Target()}
 	_, _, err = RunCmd(args)
 	return err
 }
@@ -131,22 +238,16 @@ func (p *Pane) MovePane(pane_target *Pane, focus bool) error {
 	args := []string{
 		"join-pane",
 		"-s",
-		fmt.Sprintf("%s:%d.%d", p.SessionName, p.WindowId, p.ID),
+		p.Target(),
 		"-t",
-		fmt.Sprintf("%s:%d", pane_target.SessionName, pane_target.WindowId)}
+		pane_target.windowTargetOf()}
 	if !focus {
 		args = append(args, "-d")
 	}
-	//  fmt.Sprintf("%s", p.WindowName)}
-	//  p.WindowName}
-	fmt.Println("MOVE PANE FROM ",
-		fmt.Sprintf("%s:%d.%d", p.SessionName, p.WindowId, p.ID),
-		"TO",
-		fmt.Sprintf("%s:%d", pane_target.SessionName, pane_target.WindowId))
 	_, _, err := RunCmd(args)
 	p.WindowName = pane_target.WindowName
 	p.WindowId = pane_target.WindowId
-	//  fmt.Println("SET FOCUS DIO", p.WindowName, out, args, err)
+	p.windowTarget = pane_target.windowTargetOf()
 	return err
 }
 
@@ -157,7 +258,7 @@ func (p *Pane) GetCurrentSize() (int, int, error) {
 		//  "-P", "-F", "#{pane_width}x#{pane_height}"}
 		"-p",
 		"-t",
-		fmt.Sprintf("%s:%d.%d", p.SessionName, p.WindowId, p.ID),
+		p.Target(),
 		"-F",
 		"#{pane_width}x#{pane_height}"}
 	out, _, err := RunCmd(args)
@@ -179,8 +280,7 @@ func (p *Pane) Capture() (string, error) {
 	args := []string{
 		"capture-pane",
 		"-t",
-		//  fmt.Sprintf("%%%d", p.ID),
-		fmt.Sprintf("%s:%d.%d", p.SessionName, p.WindowId, p.ID),
+		p.Target(),
 		"-p",
 	}
 
@@ -195,18 +295,153 @@ func (p *Pane) Capture() (string, error) {
 	return out, nil
 }
 
-// RunCommand runs a command in the pane.
-func (p *Pane) RunCommand(command string) error {
+// SplitDirection selects the orientation of a new pane created with Split.
+type SplitDirection int
+
+const (
+	Horizontal SplitDirection = iota
+	Vertical
+)
+
+// SplitOptions configures Pane.Split.
+type SplitOptions struct {
+	// Direction is the split orientation. Defaults to Horizontal.
+	Direction SplitDirection
+
+	// Percentage is the size of the new pane as a percentage of the
+	// window. Ignored if zero.
+	Percentage int
+
+	// Size is the absolute size (in lines/columns) of the new pane.
+	// Ignored if zero. Takes precedence over Percentage.
+	Size int
+
+	// Root is the starting directory for the new pane (-c).
+	Root string
+
+	// Command, if non-empty, is run in the new pane after it is created.
+	Command string
+
+	// Focus controls whether the new pane becomes the active pane. When
+	// false, `-d` is passed so focus stays on the source pane.
+	Focus bool
+}
+
+// Split creates a new pane by splitting p with `split-window` and returns
+// it fully populated, in the same shape ListPanes produces. Chaining
+// p.Split(...) calls targets the pane that was just created, not the
+// original one, since the returned *Pane is what the caller splits next.
+func (p *Pane) Split(opts SplitOptions) (*Pane, error) {
+	flag := "-h"
+	if opts.Direction == Vertical {
+		flag = "-v"
+	}
+
 	args := []string{
-		"send-keys",
-		"-t",
-		fmt.Sprintf("%s:%d.%d", p.SessionName, p.WindowId, p.ID),
-		command,
-		"C-m",
+		"split-window",
+		"-P", "-F", paneFormat,
+		flag,
+		"-t", p.Target(),
+	}
+
+	if opts.Root != "" {
+		args = append(args, "-c", opts.Root)
+	}
+	if opts.Size > 0 {
+		args = append(args, "-l", strconv.Itoa(opts.Size))
+	} else if opts.Percentage > 0 {
+		args = append(args, "-p", strconv.Itoa(opts.Percentage))
+	}
+	if !opts.Focus {
+		args = append(args, "-d")
+	}
+	if opts.Command != "" {
+		args = append(args, opts.Command)
+	}
+
+	out, stdErr, err := RunCmd(args)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, stdErr)
+	}
+
+	pane, ok, err := parsePaneLine(strings.TrimSpace(out))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("split-window: could not parse new pane from: %q", out)
 	}
+
+	return &pane, nil
+}
+
+// SendKeys sends each of keys to the pane as a separate send-keys
+// argument, so tmux's own key-name parsing applies to each one (e.g.
+// "C-b", ":", "Escape", "PageUp").
+func (p *Pane) SendKeys(keys ...string) error {
+	args := append([]string{"send-keys", "-t", p.Target()}, keys...)
+	_, stdErr, err := RunCmd(args)
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, stdErr)
+	}
+	return nil
+}
+
+// SendLiteral sends text to the pane verbatim (send-keys -l), disabling
+// tmux's key-name interpretation. Use this over SendKeys for pasting text
+// or shell input that may otherwise look like a key name.
+func (p *Pane) SendLiteral(text string) error {
+	args := []string{"send-keys", "-l", "-t", p.Target(), text}
 	_, stdErr, err := RunCmd(args)
 	if err != nil {
 		return fmt.Errorf("%v: %s", err, stdErr)
 	}
 	return nil
 }
+
+// RunCommand runs a command in the pane by sending it followed by Enter.
+func (p *Pane) RunCommand(command string) error {
+	return p.SendKeys(command, "C-m")
+}
+
+const (
+	idlePollInterval = 100 * time.Millisecond
+	idleStableCount  = 3
+)
+
+// RunCommandAndWait runs command in the pane and blocks until the pane's
+// output stops changing (polled via Capture) or timeout elapses. This is
+// the primitive that makes scripted integration tests against tmux
+// sessions possible: callers can run a command and know when it's done
+// without guessing a sleep duration.
+func (p *Pane) RunCommandAndWait(command string, timeout time.Duration) error {
+	if err := p.RunCommand(command); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	var lastHash [sha256.Size]byte
+	stable := 0
+
+	for time.Now().Before(deadline) {
+		time.Sleep(idlePollInterval)
+
+		out, err := p.Capture()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256([]byte(out))
+
+		if hash == lastHash {
+			stable++
+			if stable >= idleStableCount {
+				return nil
+			}
+			continue
+		}
+		stable = 0
+		lastHash = hash
+	}
+
+	return fmt.Errorf("tmux: RunCommandAndWait: pane %s did not idle within %s", p.Target(), timeout)
+}
@@ -0,0 +1,123 @@
+// The MIT License (MIT)
+// Copyright (C) 2019 Georgy Komarov <jubnzv@gmail.com>
+
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PopupOptions configures DisplayPopup.
+type PopupOptions struct {
+	// Width and Height accept tmux's own size syntax: an absolute number
+	// of cells, or a percentage like "80%". Empty means let tmux decide.
+	Width, Height string
+
+	// X and Y position the popup; empty means centered.
+	X, Y string
+
+	// Root is the popup's starting directory (-d).
+	Root string
+
+	// Env is a list of "KEY=VALUE" pairs set inside the popup (-e).
+	Env []string
+
+	// Border is tmux's -b border-lines style, e.g. "rounded", "double",
+	// "heavy", "none". Empty uses tmux's default.
+	Border string
+
+	// Title is shown on the popup's border (-T).
+	Title string
+
+	// Capture, if true, routes the command's stdout through a temp file
+	// and returns its contents, since display-popup itself discards
+	// stdout.
+	Capture bool
+
+	// Command is the command and its arguments to run inside the popup.
+	Command []string
+}
+
+// DisplayPopup runs a command in a tmux popup (display-popup, tmux >= 3.2),
+// centered unless X/Y are set, optionally capturing what it writes to stdout. This is
+// what lets interactive pickers like fzf run as first-class citizens
+// instead of requiring a dedicated pane/window.
+func DisplayPopup(opts PopupOptions) (string, error) {
+	if len(opts.Command) == 0 {
+		return "", fmt.Errorf("tmux: DisplayPopup requires a command")
+	}
+
+	args := []string{"display-popup", "-E"}
+	if opts.Width != "" {
+		args = append(args, "-w", opts.Width)
+	}
+	if opts.Height != "" {
+		args = append(args, "-h", opts.Height)
+	}
+	if opts.X != "" {
+		args = append(args, "-x", opts.X)
+	}
+	if opts.Y != "" {
+		args = append(args, "-y", opts.Y)
+	}
+	if opts.Root != "" {
+		args = append(args, "-d", opts.Root)
+	}
+	if opts.Border != "" {
+		args = append(args, "-b", opts.Border)
+	}
+	if opts.Title != "" {
+		args = append(args, "-T", opts.Title)
+	}
+	for _, e := range opts.Env {
+		args = append(args, "-e", e)
+	}
+
+	if !opts.Capture {
+		// No shell involved: tmux execs the command argv directly, so
+		// there's nothing to escape and no argument-splitting to get
+		// wrong.
+		args = append(args, opts.Command...)
+		_, stdErr, err := RunCmd(args)
+		if err != nil {
+			return "", fmt.Errorf("%v: %s", err, stdErr)
+		}
+		return "", nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "go-tmux-popup-*")
+	if err != nil {
+		return "", fmt.Errorf("tmux: create popup output file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	// Redirecting stdout to tmpPath needs a shell, but opts.Command's argv
+	// boundaries must survive it: pass the command through as positional
+	// parameters ("$@") instead of splicing it into the script text, so
+	// spaces/metacharacters in any argument can't be misread as shell
+	// syntax.
+	script := `"$@" > ` + shellQuote(tmpPath)
+	args = append(args, "sh", "-c", script, "sh")
+	args = append(args, opts.Command...)
+
+	_, stdErr, err := RunCmd(args)
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, stdErr)
+	}
+
+	out, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("tmux: read popup output: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// shellQuote single-quotes s for safe use inside a `sh -c` script.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,23 @@
+// The MIT License (MIT)
+// Copyright (C) 2019 Georgy Komarov <jubnzv@gmail.com>
+
+package tmux
+
+import "fmt"
+
+// SwapPanes swaps the position of src and dst, leaving focus where it was.
+// It complements MovePane, which joins panes into a target window instead
+// of merely trading places within/across windows.
+func SwapPanes(src, dst *Pane) error {
+	args := []string{
+		"swap-pane",
+		"-d",
+		"-s", src.Target(),
+		"-t", dst.Target(),
+	}
+	_, stdErr, err := RunCmd(args)
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, stdErr)
+	}
+	return nil
+}
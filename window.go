@@ -0,0 +1,121 @@
+// The MIT License (MIT)
+// Copyright (C) 2019 Georgy Komarov <jubnzv@gmail.com>
+
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const windowFields = 5
+
+// windowFormat is the list-windows format string parseWindowLine expects,
+// mirroring paneFormat/ParsePaneLine.
+var windowFormat = strings.Join([]string{
+	"#{session_name}",
+	"#{window_id}",
+	"#{window_name}",
+	"#{window_index}",
+	"#{window_active}",
+}, paneSep)
+
+type Window struct {
+	ID          int
+	Name        string
+	Index       int
+	SessionName string
+	Active      bool
+
+	// target caches the window's "@<window_id>" target, populated by
+	// parseWindowLine. See Target.
+	target string
+}
+
+// Target returns the stable "@<window_id>" target for this window,
+// preferring it over "session:window" since the latter breaks as soon as
+// a session/window is renamed mid-operation. Falls back to computing it
+// from ID for Windows built by hand rather than parseWindowLine.
+func (w *Window) Target() string {
+	if w.target != "" {
+		return w.target
+	}
+	return fmt.Sprintf("@%d", w.ID)
+}
+
+// parseWindowLine parses a single line produced with the windowFormat
+// format string (fields joined with sep) into a Window, symmetric with
+// ParsePaneLine.
+func parseWindowLine(line, sep string) (Window, error) {
+	fields := strings.Split(line, sep)
+	if len(fields) != windowFields {
+		return Window{}, fmt.Errorf("tmux: expected %d fields, got %d: %q", windowFields, len(fields), line)
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(fields[1], "@"))
+	if err != nil {
+		return Window{}, fmt.Errorf("tmux: parse window_id %q: %w", fields[1], err)
+	}
+
+	index, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return Window{}, fmt.Errorf("tmux: parse window_index %q: %w", fields[3], err)
+	}
+
+	return Window{
+		SessionName: fields[0],
+		ID:          id,
+		Name:        fields[2],
+		Index:       index,
+		Active:      fields[4] == "1",
+		target:      fields[1],
+	}, nil
+}
+
+// ListWindows returns the list of windows. Optional arguments define the
+// search scope with tmux command keys (see tmux(1) manpage):
+// list-windows [-a] [-t target]
+//
+//   - `-a`: target is ignored and all windows on the server are listed.
+//     If not given, target is a session (or the current session).
+func ListWindows(args []string) ([]Window, error) {
+	args = append([]string{"list-windows", "-F", windowFormat}, args...)
+
+	out, _, err := RunCmd(args)
+	if err != nil {
+		return nil, err
+	}
+
+	outLines := strings.Split(out, "\n")
+	windows := []Window{}
+
+	for _, line := range outLines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		window, err := parseWindowLine(line, paneSep)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+// SelectLayout applies a tmux layout (e.g. "even-horizontal", "tiled",
+// "main-vertical") to the window.
+func (w *Window) SelectLayout(layout string) error {
+	args := []string{
+		"select-layout",
+		"-t",
+		w.Target(),
+		layout,
+	}
+	_, stdErr, err := RunCmd(args)
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, stdErr)
+	}
+	return nil
+}